@@ -0,0 +1,172 @@
+package main
+
+import "image"
+
+// encodeRGBA32 packs img into FSH's 32-bit A8R8G8B8 bitmap format: one BGRA
+// quadruplet per pixel, row-major.
+func encodeRGBA32(img image.Image) []byte {
+  bounds := img.Bounds()
+  w, h := bounds.Dx(), bounds.Dy()
+  data := make([]byte, 0, w*h*4)
+
+  for y := 0; y < h; y++ {
+    for x := 0; x < w; x++ {
+      r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+      data = append(data, byte(b>>8), byte(g>>8), byte(r>>8), byte(a>>8))
+    }
+  }
+
+  return data
+}
+
+// encodeDXT1 block-compresses img as DXT1: every 4x4 block becomes two
+// RGB565 endpoint colors followed by sixteen 2-bit palette indices.
+func encodeDXT1(img image.Image) []byte {
+  return encodeBlocks(img, func(block [16][4]uint8) []byte {
+    return encodeDXT1Block(block)
+  })
+}
+
+// encodeDXT3 block-compresses img as DXT3: every 4x4 block becomes an
+// explicit 4-bit alpha block followed by a DXT1-style color block (alpha is
+// ignored when choosing the color endpoints).
+func encodeDXT3(img image.Image) []byte {
+  return encodeBlocks(img, func(block [16][4]uint8) []byte {
+    out := make([]byte, 0, 16)
+    out = append(out, encodeDXT3AlphaBlock(block)...)
+    out = append(out, encodeDXT1Block(block)...)
+    return out
+  })
+}
+
+// encodeBlocks walks img in 4x4 pixel blocks, in row-major block order, and
+// concatenates encodeBlock's output for each one.
+func encodeBlocks(img image.Image, encodeBlock func([16][4]uint8) []byte) []byte {
+  bounds := img.Bounds()
+  w, h := bounds.Dx(), bounds.Dy()
+
+  var data []byte
+  for by := 0; by < h; by += 4 {
+    for bx := 0; bx < w; bx += 4 {
+      var block [16][4]uint8
+      for py := 0; py < 4; py++ {
+        for px := 0; px < 4; px++ {
+          r, g, b, a := img.At(bounds.Min.X+bx+px, bounds.Min.Y+by+py).RGBA()
+          block[py*4+px] = [4]uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+        }
+      }
+      data = append(data, encodeBlock(block)...)
+    }
+  }
+
+  return data
+}
+
+// encodeDXT1Block compresses a single 4x4 block into 8 bytes: two RGB565
+// endpoints (the pixels with the highest and lowest luminance) followed by
+// sixteen 2-bit indices into the four colors they imply.
+func encodeDXT1Block(block [16][4]uint8) []byte {
+  maxIdx, minIdx := 0, 0
+  maxLum, minLum := -1, 1<<30
+
+  for i, px := range block {
+    lum := int(px[0])*299 + int(px[1])*587 + int(px[2])*114
+    if lum > maxLum {
+      maxLum, maxIdx = lum, i
+    }
+    if lum < minLum {
+      minLum, minIdx = lum, i
+    }
+  }
+
+  c0 := rgbTo565(block[maxIdx][0], block[maxIdx][1], block[maxIdx][2])
+  c1 := rgbTo565(block[minIdx][0], block[minIdx][1], block[minIdx][2])
+
+  if c0 == c1 {
+    // Force the 4-color (not punch-through alpha) interpretation even when
+    // the block is a single flat color.
+    if c0 == 0xFFFF {
+      c1--
+    } else {
+      c0++
+    }
+  } else if c0 < c1 {
+    c0, c1 = c1, c0
+  }
+
+  palette := dxt1Palette(c0, c1)
+
+  out := make([]byte, 8)
+  putUint16LE(out[0:2], c0)
+  putUint16LE(out[2:4], c1)
+
+  var indices uint32
+  for i, px := range block {
+    idx := closestPaletteIndex(palette, px)
+    indices |= uint32(idx) << uint(i*2)
+  }
+  putUint32LE(out[4:8], indices)
+
+  return out
+}
+
+// encodeDXT3AlphaBlock packs a 4x4 block's alpha channel into DXT3's
+// explicit format: one 4-bit alpha value per pixel, two pixels per byte.
+func encodeDXT3AlphaBlock(block [16][4]uint8) []byte {
+  out := make([]byte, 8)
+  for i, px := range block {
+    nibble := uint16(px[3]) >> 4
+    byteIdx := i / 2
+    if i%2 == 0 {
+      out[byteIdx] = byte(nibble)
+    } else {
+      out[byteIdx] |= byte(nibble << 4)
+    }
+  }
+  return out
+}
+
+// rgbTo565 packs 8-bit RGB components into a 16-bit RGB565 value.
+func rgbTo565(r, g, b uint8) uint16 {
+  return (uint16(r)>>3)<<11 | (uint16(g)>>2)<<5 | (uint16(b) >> 3)
+}
+
+// rgb565ToRGB unpacks a 16-bit RGB565 value into 8-bit RGB components.
+func rgb565ToRGB(c uint16) (r, g, b uint8) {
+  r = uint8((c>>11)&0x1F) << 3
+  g = uint8((c>>5)&0x3F) << 2
+  b = uint8(c&0x1F) << 3
+  return r, g, b
+}
+
+// dxt1Palette derives the four colors a DXT1 block's indices select between,
+// given its two endpoint colors with c0 > c1 (the 4-color interpretation).
+func dxt1Palette(c0, c1 uint16) [4][3]uint8 {
+  r0, g0, b0 := rgb565ToRGB(c0)
+  r1, g1, b1 := rgb565ToRGB(c1)
+
+  return [4][3]uint8{
+    {r0, g0, b0},
+    {r1, g1, b1},
+    {uint8((2*int(r0) + int(r1)) / 3), uint8((2*int(g0) + int(g1)) / 3), uint8((2*int(b0) + int(b1)) / 3)},
+    {uint8((int(r0) + 2*int(r1)) / 3), uint8((int(g0) + 2*int(g1)) / 3), uint8((int(b0) + 2*int(b1)) / 3)},
+  }
+}
+
+// closestPaletteIndex returns the index of the palette entry nearest px in
+// squared RGB distance.
+func closestPaletteIndex(palette [4][3]uint8, px [4]uint8) uint8 {
+  best, bestDist := 0, 1<<30
+
+  for i, c := range palette {
+    dr := int(c[0]) - int(px[0])
+    dg := int(c[1]) - int(px[1])
+    db := int(c[2]) - int(px[2])
+    dist := dr*dr + dg*dg + db*db
+    if dist < bestDist {
+      best, bestDist = i, dist
+    }
+  }
+
+  return uint8(best)
+}
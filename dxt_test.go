@@ -0,0 +1,124 @@
+package main
+
+import (
+  "image"
+  "image/color"
+  "testing"
+)
+
+// decodeDXT1Block is the inverse of encodeDXT1Block, used only to verify
+// round-trip fidelity in tests.
+func decodeDXT1Block(block []byte) [16][3]uint8 {
+  c0 := uint16(block[0]) | uint16(block[1])<<8
+  c1 := uint16(block[2]) | uint16(block[3])<<8
+  indices := uint32(block[4]) | uint32(block[5])<<8 | uint32(block[6])<<16 | uint32(block[7])<<24
+
+  palette := dxt1Palette(c0, c1)
+
+  var out [16][3]uint8
+  for i := 0; i < 16; i++ {
+    idx := (indices >> uint(i*2)) & 0x3
+    out[i] = palette[idx]
+  }
+  return out
+}
+
+func TestEncodeDXT1BlockRoundTrip(t *testing.T) {
+  // A block of only two colors is exactly representable by DXT1's two
+  // endpoints, so the round trip should reproduce it closely.
+  block := [16][4]uint8{}
+  for i := range block {
+    if i%2 == 0 {
+      block[i] = [4]uint8{255, 0, 0, 255}
+    } else {
+      block[i] = [4]uint8{0, 0, 255, 255}
+    }
+  }
+
+  encoded := encodeDXT1Block(block)
+  if len(encoded) != 8 {
+    t.Fatalf("encodeDXT1Block returned %d bytes, want 8", len(encoded))
+  }
+
+  decoded := decodeDXT1Block(encoded)
+  for i, px := range block {
+    got := decoded[i]
+    dr := int(got[0]) - int(px[0])
+    dg := int(got[1]) - int(px[1])
+    db := int(got[2]) - int(px[2])
+    if dr*dr+dg*dg+db*db > 16*16*3 {
+      t.Errorf("pixel %d: decoded %v too far from source %v", i, got, px)
+    }
+  }
+}
+
+func TestEncodeDXT1FlatBlockAvoidsPunchThroughAlpha(t *testing.T) {
+  block := [16][4]uint8{}
+  for i := range block {
+    block[i] = [4]uint8{128, 128, 128, 255}
+  }
+
+  encoded := encodeDXT1Block(block)
+  c0 := uint16(encoded[0]) | uint16(encoded[1])<<8
+  c1 := uint16(encoded[2]) | uint16(encoded[3])<<8
+  if c0 <= c1 {
+    t.Errorf("flat block encoded with c0=%x <= c1=%x, want c0 > c1 to force the 4-color interpretation", c0, c1)
+  }
+}
+
+func TestEncodeDXT3AlphaBlock(t *testing.T) {
+  block := [16][4]uint8{}
+  for i := range block {
+    block[i] = [4]uint8{0, 0, 0, uint8(i * 17)}
+  }
+
+  alpha := encodeDXT3AlphaBlock(block)
+  if len(alpha) != 8 {
+    t.Fatalf("encodeDXT3AlphaBlock returned %d bytes, want 8", len(alpha))
+  }
+
+  for i, px := range block {
+    byteIdx := i / 2
+    var nibble byte
+    if i%2 == 0 {
+      nibble = alpha[byteIdx] & 0xF
+    } else {
+      nibble = (alpha[byteIdx] >> 4) & 0xF
+    }
+    if want := px[3] >> 4; nibble != want {
+      t.Errorf("pixel %d: alpha nibble %x, want %x", i, nibble, want)
+    }
+  }
+}
+
+func TestEncodeDXT1ImageSize(t *testing.T) {
+  img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+  for y := 0; y < 4; y++ {
+    for x := 0; x < 8; x++ {
+      img.Set(x, y, color.RGBA{R: uint8(x * 16), G: uint8(y * 32), B: 0, A: 255})
+    }
+  }
+
+  data := encodeDXT1(img)
+  wantBlocks := (8 / 4) * (4 / 4)
+  if got := len(data); got != wantBlocks*8 {
+    t.Errorf("encodeDXT1 returned %d bytes, want %d", got, wantBlocks*8)
+  }
+}
+
+func TestEncodeRGBA32(t *testing.T) {
+  img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+  img.Set(0, 0, color.RGBA{R: 10, G: 20, B: 30, A: 255})
+  img.Set(1, 0, color.RGBA{R: 40, G: 50, B: 60, A: 128})
+
+  data := encodeRGBA32(img)
+  want := []byte{30, 20, 10, 255, 60, 50, 40, 128}
+  if len(data) != len(want) {
+    t.Fatalf("encodeRGBA32 returned %d bytes, want %d", len(data), len(want))
+  }
+  for i := range want {
+    if data[i] != want[i] {
+      t.Errorf("byte %d = %d, want %d", i, data[i], want[i])
+    }
+  }
+}
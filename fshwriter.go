@@ -0,0 +1,205 @@
+package main
+
+import (
+  "encoding/json"
+  "fmt"
+  "image"
+  "os"
+  "sort"
+)
+
+// FshEncoding selects the pixel subformat used to store each bitmap inside
+// an FSH file written by FshWriter.
+type FshEncoding string
+
+const (
+  // FshEncodingDXT1 stores each bitmap as a block-compressed DXT1 texture
+  // (no alpha channel).
+  FshEncodingDXT1 FshEncoding = "dxt1"
+
+  // FshEncodingDXT3 stores each bitmap as a block-compressed DXT3 texture
+  // with an explicit 4-bit alpha channel.
+  FshEncodingDXT3 FshEncoding = "dxt3"
+
+  // FshEncodingRGBA32 stores each bitmap uncompressed, 32 bits per pixel.
+  FshEncodingRGBA32 FshEncoding = "rgba32"
+)
+
+// FSH bitmap record IDs, as documented by the SC4 modding community.
+const (
+  fshRecordDXT1   = 0x60
+  fshRecordDXT3   = 0x61
+  fshRecordRGBA32 = 0x7D
+)
+
+// FshWriter serializes a set of prototype images as EA's FSH bitmap
+// container so the output can be dropped directly into a SimCity 4 DAT.
+// Only mip level 0 is written, at each image's own dimensions.
+type FshWriter struct {
+  // Encoding selects the bitmap subformat written for every entry.
+  Encoding FshEncoding
+}
+
+// NewFshWriter creates an FshWriter that encodes bitmaps using encoding.
+func NewFshWriter(encoding FshEncoding) *FshWriter {
+  return &FshWriter{Encoding: encoding}
+}
+
+// Write serializes images, keyed by the same hash ImageSet uses for PNG
+// filenames, into path as a single FSH file.  It also writes a companion
+// instance-ID manifest named path + ".manifest.json" mapping each hex hash
+// to the 32-bit instance ID assigned to its directory entry, so downstream
+// DAT-packer tools can build a TGI index without re-deriving it.
+func (w *FshWriter) Write(path string, images map[uint64]image.Image) error {
+  keys := make([]uint64, 0, len(images))
+  for key := range images {
+    keys = append(keys, key)
+  }
+  sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+  entries := make([][]byte, len(keys))
+  for i, key := range keys {
+    data, e := w.encodeEntry(images[key])
+    if e != nil {
+      return fmt.Errorf("encoding FSH entry %x: %w", key, e)
+    }
+    entries[i] = data
+  }
+
+  file, e := os.Create(path)
+  if e != nil {
+    return e
+  }
+  defer file.Close()
+
+  const headerSize = 16
+  dirSize := len(keys) * 8
+
+  totalSize := uint32(headerSize + dirSize)
+  for _, entry := range entries {
+    totalSize += uint32(len(entry))
+  }
+
+  if e := writeFshHeader(file, len(keys), totalSize); e != nil {
+    return e
+  }
+
+  offset := uint32(headerSize + dirSize)
+  for i, key := range keys {
+    if e := writeFshDirEntry(file, key, offset); e != nil {
+      return e
+    }
+    offset += uint32(len(entries[i]))
+  }
+
+  for _, entry := range entries {
+    if _, e := file.Write(entry); e != nil {
+      return e
+    }
+  }
+
+  return w.writeManifest(path+".manifest.json", keys)
+}
+
+// writeManifest writes the hex-hash to instance-ID mapping used by the FSH
+// directory, so a downstream DAT packer can reconstruct the same TGI
+// instance values without recomputing them.
+func (w *FshWriter) writeManifest(path string, keys []uint64) error {
+  manifest := make(map[string]string, len(keys))
+  for _, key := range keys {
+    manifest[fmt.Sprintf("%x", key)] = fmt.Sprintf("%08X", instanceID(key))
+  }
+
+  data, e := json.MarshalIndent(manifest, "", "  ")
+  if e != nil {
+    return e
+  }
+
+  return os.WriteFile(path, data, 0644)
+}
+
+// instanceID derives the 32-bit TGI instance ID used for a directory entry
+// from its 64-bit hash.
+func instanceID(key uint64) uint32 {
+  return uint32(key)
+}
+
+// writeFshHeader writes the FSH file header: the "SHPI" magic, the total
+// file size, the entry count, and a directory ID.
+func writeFshHeader(file *os.File, numEntries int, totalSize uint32) error {
+  header := make([]byte, 16)
+  copy(header[0:4], []byte("SHPI"))
+  putUint32LE(header[4:8], totalSize)
+  putUint32LE(header[8:12], uint32(numEntries))
+  copy(header[12:16], []byte("G264"))
+
+  _, e := file.Write(header)
+  return e
+}
+
+// writeFshDirEntry writes one FSH directory entry: a 4-byte entry ID (the
+// low 32 bits of the prototype's hash) and the byte offset of its bitmap
+// data from the start of the file.
+func writeFshDirEntry(file *os.File, key uint64, offset uint32) error {
+  entry := make([]byte, 8)
+  putUint32LE(entry[0:4], uint32(key))
+  putUint32LE(entry[4:8], offset)
+
+  _, e := file.Write(entry)
+  return e
+}
+
+// encodeEntry builds the EntryHeader and pixel data for a single bitmap,
+// using w.Encoding.
+func (w *FshWriter) encodeEntry(img image.Image) ([]byte, error) {
+  var recordID byte
+  var pixels []byte
+
+  switch w.Encoding {
+  case FshEncodingDXT1:
+    recordID = fshRecordDXT1
+    pixels = encodeDXT1(img)
+  case FshEncodingDXT3:
+    recordID = fshRecordDXT3
+    pixels = encodeDXT3(img)
+  case FshEncodingRGBA32:
+    recordID = fshRecordRGBA32
+    pixels = encodeRGBA32(img)
+  default:
+    return nil, fmt.Errorf("unsupported FSH encoding %q", w.Encoding)
+  }
+
+  bounds := img.Bounds()
+
+  const entryHeaderSize = 16
+  entry := make([]byte, entryHeaderSize+len(pixels))
+
+  entry[0] = recordID
+  putUint24LE(entry[1:4], uint32(len(entry)))
+  putUint16LE(entry[4:6], uint16(bounds.Dx()))
+  putUint16LE(entry[6:8], uint16(bounds.Dy()))
+  // entry[8:16] is the mip/center "misc data" block; mip level 0 only, so
+  // it's left zeroed.
+
+  copy(entry[entryHeaderSize:], pixels)
+
+  return entry, nil
+}
+
+func putUint16LE(b []byte, v uint16) {
+  b[0] = byte(v)
+  b[1] = byte(v >> 8)
+}
+
+func putUint24LE(b []byte, v uint32) {
+  b[0] = byte(v)
+  b[1] = byte(v >> 8)
+  b[2] = byte(v >> 16)
+}
+
+func putUint32LE(b []byte, v uint32) {
+  b[0] = byte(v)
+  b[1] = byte(v >> 8)
+  b[2] = byte(v >> 16)
+  b[3] = byte(v >> 24)
+}
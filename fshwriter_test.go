@@ -0,0 +1,86 @@
+package main
+
+import (
+  "image"
+  "image/color"
+  "os"
+  "path/filepath"
+  "testing"
+)
+
+func TestEncodeEntryUsesImageDimensions(t *testing.T) {
+  w := NewFshWriter(FshEncodingRGBA32)
+
+  img := image.NewRGBA(image.Rect(0, 0, 64, 32))
+  for y := 0; y < 32; y++ {
+    for x := 0; x < 64; x++ {
+      img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 0, A: 255})
+    }
+  }
+
+  entry, e := w.encodeEntry(img)
+  if e != nil {
+    t.Fatalf("encodeEntry returned error: %v", e)
+  }
+
+  const entryHeaderSize = 16
+  gotWidth := uint16(entry[4]) | uint16(entry[5])<<8
+  gotHeight := uint16(entry[6]) | uint16(entry[7])<<8
+  if gotWidth != 64 || gotHeight != 32 {
+    t.Errorf("entry header width/height = %d/%d, want 64/32", gotWidth, gotHeight)
+  }
+
+  wantPixels := len(encodeRGBA32(img))
+  if got := len(entry) - entryHeaderSize; got != wantPixels {
+    t.Errorf("entry pixel payload is %d bytes, want %d", got, wantPixels)
+  }
+}
+
+func TestFshWriterWriteHeaderAndDirectory(t *testing.T) {
+  w := NewFshWriter(FshEncodingDXT1)
+
+  images := map[uint64]image.Image{
+    0x1: image.NewRGBA(image.Rect(0, 0, 4, 4)),
+    0x2: image.NewRGBA(image.Rect(0, 0, 4, 4)),
+  }
+
+  path := filepath.Join(t.TempDir(), "out.fsh")
+  if e := w.Write(path, images); e != nil {
+    t.Fatalf("Write returned error: %v", e)
+  }
+
+  data, e := os.ReadFile(path)
+  if e != nil {
+    t.Fatalf("reading written FSH file: %v", e)
+  }
+
+  if string(data[0:4]) != "SHPI" {
+    t.Fatalf("header magic = %q, want %q", data[0:4], "SHPI")
+  }
+
+  totalSize := uint32(data[4]) | uint32(data[5])<<8 | uint32(data[6])<<16 | uint32(data[7])<<24
+  if int(totalSize) != len(data) {
+    t.Errorf("header total size = %d, want %d (actual file size)", totalSize, len(data))
+  }
+
+  numEntries := uint32(data[8]) | uint32(data[9])<<8 | uint32(data[10])<<16 | uint32(data[11])<<24
+  if numEntries != uint32(len(images)) {
+    t.Errorf("header entry count = %d, want %d", numEntries, len(images))
+  }
+
+  if string(data[12:16]) != "G264" {
+    t.Errorf("header directory ID = %q, want %q", data[12:16], "G264")
+  }
+
+  const headerSize = 16
+  dirOffset := headerSize
+  firstEntryOffset := uint32(data[dirOffset+4]) | uint32(data[dirOffset+5])<<8 | uint32(data[dirOffset+6])<<16 | uint32(data[dirOffset+7])<<24
+  if firstEntryOffset != uint32(headerSize+len(images)*8) {
+    t.Errorf("first directory entry offset = %d, want %d", firstEntryOffset, headerSize+len(images)*8)
+  }
+
+  manifestPath := path + ".manifest.json"
+  if _, e := os.Stat(manifestPath); e != nil {
+    t.Errorf("expected manifest file at %s: %v", manifestPath, e)
+  }
+}
@@ -1,12 +1,32 @@
 package main
 
 import (
+  "bytes"
+  "encoding/base64"
+  "encoding/json"
   "fmt"
   "image"
+  _ "image/jpeg"
   "image/png"
+  "io"
+  "math"
   "os"
+  "runtime"
+  "sync"
 
+  "github.com/buckket/go-blurhash"
   "github.com/disintegration/imaging"
+  "github.com/rwcarlsen/goexif/exif"
+  _ "golang.org/x/image/bmp"
+  _ "golang.org/x/image/tiff"
+  _ "golang.org/x/image/webp"
+)
+
+// blurhashXComponents and blurhashYComponents set the resolution of the
+// blurhash placeholder computed for each prototype image.
+const (
+  blurhashXComponents = 4
+  blurhashYComponents = 3
 )
 
 // ImageSet is used to build a unique set of images.  As image.Image instances
@@ -41,17 +61,194 @@ type ImageSet struct {
 
   // stride consists of the number of images that exist in each row.
   stride int
+
+  // HashMode selects the algorithm used to detect duplicate tiles.
+  HashMode HashMode
+
+  // Threshold is the maximum distance under which two tiles are considered
+  // duplicates when HashMode is HashModePHash (a Hamming distance) or
+  // HashModeRadon (an L2 distance).  A value of 0 disables perceptual
+  // matching and falls back to exact comparison only.
+  Threshold float64
+
+  // protoKeys holds the exact hash of each prototype image, in the order the
+  // prototype was first added to protoImages.  protoFingerprints and
+  // protoSignatures are parallel to this slice.
+  protoKeys []uint64
+
+  // protoFingerprints holds the PHash of each prototype image, parallel to
+  // protoKeys.  Only populated when HashMode is HashModePHash.
+  protoFingerprints []uint64
+
+  // protoSignatures holds the RadonSignature of each prototype image,
+  // parallel to protoKeys.  Only populated when HashMode is HashModeRadon.
+  protoSignatures [][]float64
+
+  // protoBlurhashes holds the blurhash string of each prototype image, keyed
+  // by the same exact hash used by protoImages.  It is computed once, when
+  // the prototype is first added.
+  protoBlurhashes map[uint64]string
+
+  // Format selects how WriteImageFiles serializes the prototype images:
+  // "png" (the default, one PNG per prototype), "fsh" (a single FSH bitmap
+  // container plus an instance-ID manifest), or "dat" (the same FSH
+  // container, named for direct inclusion in a DAT packer's input list).
+  Format string
+
+  // FshEncoding selects the bitmap subformat used when Format is "fsh" or
+  // "dat".
+  FshEncoding FshEncoding
+
+  // SourceOrientation is the EXIF orientation value (1-8) detected in the
+  // source image, or 0 if the source wasn't a JPEG or carried no
+  // orientation tag.  It is surfaced in the HTML report for verification.
+  SourceOrientation int
+
+  // Workers is the number of goroutines used to crop and hash tiles, and to
+  // encode output PNGs, concurrently.  A value <= 0 means
+  // runtime.NumCPU().
+  Workers int
+
+  // ServeAddr, when non-empty, makes Process start an interactive HTTP tile
+  // browser on this address (see Serve) instead of writing report.html and
+  // the images directory.
+  ServeAddr string
+
+  // TileWidth and TileHeight are the dimensions of each uniform tile
+  // extracted from the source image.  A value <= 0 means 128.  Ignored when
+  // GridPath is set.
+  TileWidth, TileHeight int
+
+  // GridPath, when non-empty, is the path to a sidecar JSON file listing
+  // the exact pixel rectangles to extract as tiles, for atlases whose tiles
+  // aren't all the same size.  It overrides TileWidth/TileHeight.
+  GridPath string
+
+  // cellX and cellY hold the pixel origin of each tile, parallel to
+  // p.images.  Only populated when GridPath is set, since a non-uniform
+  // grid has no meaningful row/column stride; GetX/GetY fall back to
+  // stride arithmetic otherwise.
+  cellX, cellY []int
 }
 
 // NewImageSet creates a new image set with the image located at the provided
-// file path.
-func NewImageSet(imagePath string) *ImageSet {
-  return &ImageSet{SourceImagePath: imagePath, protoImages: make(map[uint64]image.Image)}
+// file path.  hashMode and threshold configure how commitTile decides
+// whether a tile duplicates an existing prototype; see ImageSet.HashMode and
+// ImageSet.Threshold.  format and fshEncoding configure how WriteImageFiles
+// serializes the result; see ImageSet.Format and ImageSet.FshEncoding.
+// workers configures how much of the work is parallelized; see
+// ImageSet.Workers.  serveAddr configures the interactive HTTP tile browser;
+// see ImageSet.ServeAddr.  tileWidth, tileHeight, and gridPath configure how
+// the source image is partitioned into tiles; see ImageSet.TileWidth,
+// ImageSet.TileHeight, and ImageSet.GridPath.
+func NewImageSet(imagePath string, hashMode HashMode, threshold float64, format string, fshEncoding FshEncoding, workers int, serveAddr string, tileWidth, tileHeight int, gridPath string) *ImageSet {
+  return &ImageSet{
+    SourceImagePath: imagePath,
+    protoImages:     make(map[uint64]image.Image),
+    protoBlurhashes: make(map[uint64]string),
+    HashMode:        hashMode,
+    Threshold:       threshold,
+    Format:          format,
+    FshEncoding:     fshEncoding,
+    Workers:         workers,
+    ServeAddr:       serveAddr,
+    TileWidth:       tileWidth,
+    TileHeight:      tileHeight,
+    GridPath:        gridPath,
+  }
+}
+
+// tileWidth returns p.TileWidth, or 128 if it is unset.
+func (p *ImageSet) tileWidth() int {
+  if p.TileWidth > 0 {
+    return p.TileWidth
+  }
+  return 128
+}
+
+// tileHeight returns p.TileHeight, or 128 if it is unset.
+func (p *ImageSet) tileHeight() int {
+  if p.TileHeight > 0 {
+    return p.TileHeight
+  }
+  return 128
+}
+
+// gridRectSpec is the on-disk JSON shape of one rectangle in a GridPath
+// sidecar file: a pixel region of the source image to treat as one tile.
+type gridRectSpec struct {
+  X int `json:"x"`
+  Y int `json:"y"`
+  W int `json:"w"`
+  H int `json:"h"`
+}
+
+// loadGridRects reads a GridPath sidecar file: a flat JSON array of pixel
+// rectangles, used instead of a uniform TileWidth x TileHeight grid so
+// atlases with mixed tile sizes can be partitioned correctly.
+func loadGridRects(path string) ([]image.Rectangle, error) {
+  data, e := os.ReadFile(path)
+  if e != nil {
+    return nil, e
+  }
+
+  var specs []gridRectSpec
+  if e := json.Unmarshal(data, &specs); e != nil {
+    return nil, e
+  }
+
+  rects := make([]image.Rectangle, len(specs))
+  for i, spec := range specs {
+    rects[i] = image.Rect(spec.X, spec.Y, spec.X+spec.W, spec.Y+spec.H)
+  }
+
+  return rects, nil
+}
+
+// tileJob identifies the source image region of a single tile waiting to be
+// cropped and hashed by the worker pool.
+type tileJob struct {
+  index int
+  rect  image.Rectangle
+}
+
+// tileResult holds the work computed for one tile by a worker: the original
+// (untransformed) tile image, the exact hash of each orientation valid for
+// its dimensions, and - depending on HashMode - the perceptual fingerprint
+// or signature of each one.  Committing a tileResult into the ImageSet's
+// maps is the only part of tile processing that isn't safe to run
+// concurrently.
+type tileResult struct {
+  index int
+  img   image.Image
+
+  // orientations lists the indices, into hashes/fingerprints/signatures,
+  // that are valid for this tile's dimensions: all eight for a square tile,
+  // or just {0, 2, 4, 6} (identity, 180 degrees, and their mirrors) for a
+  // non-square tile,
+  // since a 90/270 degree rotation would transpose its width and height.
+  orientations []int
+
+  hashes       [8]uint64
+  fingerprints [8]uint64
+  signatures   [][]float64
+}
+
+// numWorkers returns p.Workers, or runtime.NumCPU() if it is unset.
+func (p *ImageSet) numWorkers() int {
+  if p.Workers > 0 {
+    return p.Workers
+  }
+  return runtime.NumCPU()
 }
 
 // Process begins the process of loading the source image, partitioning it, and
 // determining all of the unique images.  Once complete, it produces a report
 // which is written to a file called report.html in the current directory.
+// Tile cropping and hashing is fanned out across a worker pool sized by
+// p.Workers; only the insertion of results into the prototype map is
+// serialized.  When p.ServeAddr is set, Process serves the tile browser
+// instead and writes neither the image files nor report.html.
 func (p *ImageSet) Process() {
   sourceImage := p.getSourceImage()
   if sourceImage == nil {
@@ -59,38 +256,117 @@ func (p *ImageSet) Process() {
   }
 
   bounds := sourceImage.Bounds()
-  numX, numY := calculateNumberImages(bounds)
 
-  p.stride = numX
-  p.images = make([]uint64, numX * numY)
-  p.orientations = make([]byte, numX * numY)
+  var rects []image.Rectangle
+  if p.GridPath != "" {
+    loaded, e := loadGridRects(p.GridPath)
+    if e != nil {
+      fmt.Fprintf(os.Stderr, "An error occurred while loading the grid file %s. Error: %s\n", p.GridPath, e)
+      return
+    }
+    rects = loaded
+
+    p.cellX = make([]int, len(rects))
+    p.cellY = make([]int, len(rects))
+    for i, rect := range rects {
+      p.cellX[i] = rect.Min.X
+      p.cellY[i] = rect.Min.Y
+    }
+  } else {
+    tileWidth, tileHeight := p.tileWidth(), p.tileHeight()
+    numX, numY := calculateNumberImages(bounds, tileWidth, tileHeight)
+    p.stride = numX
+
+    for y := 0; y < numY; y++ {
+      for x := 0; x < numX; x++ {
+        rects = append(rects, image.Rect(x*tileWidth, y*tileHeight, (x+1)*tileWidth, (y+1)*tileHeight))
+      }
+    }
+  }
+
+  numTiles := len(rects)
+  p.images = make([]uint64, numTiles)
+  p.orientations = make([]byte, numTiles)
+
+  jobs := make(chan tileJob, numTiles)
+  results := make(chan tileResult, numTiles)
+
+  var workers sync.WaitGroup
+  workers.Add(p.numWorkers())
+  for i := 0; i < p.numWorkers(); i++ {
+    go func() {
+      defer workers.Done()
+      for job := range jobs {
+        tile := imaging.Crop(sourceImage, job.rect)
+        results <- p.computeTile(tile, job.index)
+      }
+    }()
+  }
+
+  for i, rect := range rects {
+    jobs <- tileJob{index: i, rect: rect}
+  }
+  close(jobs)
+
+  go func() {
+    workers.Wait()
+    close(results)
+  }()
 
-  for y := 0; y < numY; y++ {
-    for x := 0; x < numX; x++ {
-      bounds := image.Rect(x * 128, y * 128, (x + 1) * 128, (y + 1) * 128)
-      p.AddImage(imaging.Crop(sourceImage, bounds), x, y)
+  p.commitResultsInOrder(results, numTiles)
+
+  if p.ServeAddr != "" {
+    if e := p.Serve(p.ServeAddr); e != nil {
+      fmt.Fprintf(os.Stderr, "An error occurred while serving the tile browser. Error: %s\n", e)
     }
+    return
   }
 
   p.WriteImageFiles()
   p.WriteReport(bounds)
 }
 
-// calculateNumberImages calculates the number of 128x128 images to extract from
-// the source image.
-func calculateNumberImages(bounds image.Rectangle) (x, y int) {
-  y = int((bounds.Max.Y - bounds.Min.Y) / 128)
-  x = calculateStride(bounds)
+// commitResultsInOrder drains results, committing each one via commitTile in
+// ascending tile-index order regardless of the order workers complete them
+// in, so the prototypes chosen match a serial, single-threaded run exactly.
+func (p *ImageSet) commitResultsInOrder(results <-chan tileResult, numTiles int) {
+  pending := make(map[int]tileResult, numTiles)
+  next := 0
+
+  for result := range results {
+    pending[result.index] = result
+
+    for {
+      r, ok := pending[next]
+      if !ok {
+        break
+      }
+      p.commitTile(r)
+      delete(pending, next)
+      next++
+    }
+  }
+}
+
+// calculateNumberImages calculates the number of tileWidth x tileHeight
+// images to extract from the source image.
+func calculateNumberImages(bounds image.Rectangle, tileWidth, tileHeight int) (x, y int) {
+  y = int((bounds.Max.Y - bounds.Min.Y) / tileHeight)
+  x = calculateStride(bounds, tileWidth)
 
   return x, y
 }
 
-func calculateStride(bounds image.Rectangle) int {
-  return int((bounds.Max.X - bounds.Min.X) / 128)
+func calculateStride(bounds image.Rectangle, tileWidth int) int {
+  return int((bounds.Max.X - bounds.Min.X) / tileWidth)
 }
 
-// getSourceImage handles loading the source image from the file path set in the
-// receiver.
+// getSourceImage handles loading the source image from the file path set in
+// the receiver.  The format (PNG, JPEG, BMP, TIFF, or WebP) is detected
+// automatically.  If the source is a JPEG carrying an EXIF Orientation tag,
+// the image is rotated/flipped so the tile grid always corresponds to the
+// intended orientation; the detected value is recorded in
+// p.SourceOrientation.
 func (p *ImageSet) getSourceImage() image.Image {
   file, e := os.Open(p.SourceImagePath)
   if e != nil {
@@ -103,79 +379,274 @@ func (p *ImageSet) getSourceImage() image.Image {
     }
     return nil
   }
+  defer file.Close()
 
-  image, e := png.Decode(file)
+  data, e := io.ReadAll(file)
+  if e != nil {
+    fmt.Fprintf(os.Stderr, "An error occurred while reading the source image file %s. Error: %s\n", p.SourceImagePath, e)
+    return nil
+  }
+
+  img, format, e := image.Decode(bytes.NewReader(data))
   if e != nil {
     fmt.Fprintf(os.Stderr, "An error occurred while loading the source image. Error: %s\n", e)
     return nil
   }
-  defer file.Close()
 
-  return image
+  if format == "jpeg" {
+    p.SourceOrientation = readExifOrientation(data)
+    img = applyExifOrientation(img, p.SourceOrientation)
+  }
+
+  return img
 }
 
-// AddImage examines the provided image and compares it to the images already
-// stored in the map of prototype images.  If this image is unique and doesn't
-// match any of the prototype images, including rotations or mirror copies, then
-// it is added to the map of prototype images.
-func (p *ImageSet) AddImage(img image.Image, x, y int) {
-  index := y * p.stride + x
-  images := make([]image.Image, 8)
-  images[0] = img
-  images[1] = imaging.Rotate90(img)
-  images[2] = imaging.Rotate180(img)
-  images[3] = imaging.Rotate270(img)
-  images[4] = imaging.FlipH(img)
-  images[5] = imaging.Rotate90(images[4])
-  images[6] = imaging.Rotate180(images[4])
-  images[7] = imaging.Rotate270(images[4])
-
-  found := false
-  firstHash := uint64(0)
-  for i := 0; i < 8; i++ {
-    hash := Hash(images[i])
-    if i == 0 {
-      firstHash = hash
+// readExifOrientation parses the EXIF Orientation tag out of JPEG data,
+// returning 0 if the data carries no EXIF metadata or no orientation tag.
+func readExifOrientation(data []byte) int {
+  x, e := exif.Decode(bytes.NewReader(data))
+  if e != nil {
+    return 0
+  }
+
+  tag, e := x.Get(exif.Orientation)
+  if e != nil {
+    return 0
+  }
+
+  orientation, e := tag.Int(0)
+  if e != nil {
+    return 0
+  }
+
+  return orientation
+}
+
+// applyExifOrientation rotates/flips img according to the EXIF orientation
+// values 1 through 8:
+//
+//  1 = identity            5 = Transpose
+//  2 = FlipH                6 = Rotate270
+//  3 = Rotate180            7 = Transverse
+//  4 = FlipV                8 = Rotate90
+//
+// Any other value is treated as identity.
+func applyExifOrientation(img image.Image, orientation int) image.Image {
+  switch orientation {
+  case 2:
+    return imaging.FlipH(img)
+  case 3:
+    return imaging.Rotate180(img)
+  case 4:
+    return imaging.FlipV(img)
+  case 5:
+    return imaging.Transpose(img)
+  case 6:
+    return imaging.Rotate270(img)
+  case 7:
+    return imaging.Transverse(img)
+  case 8:
+    return imaging.Rotate90(img)
+  default:
+    return img
+  }
+}
+
+// computeTile generates the orientations of img valid for its dimensions -
+// all eight rotated/mirrored variants for a square tile, or just the four
+// that preserve width and height for a non-square one - and hashes each
+// with the exact hash, plus p.HashMode's perceptual hash when applicable.
+// It does no map access and is safe to call concurrently; the result is
+// later passed to commitTile, which is not.
+func (p *ImageSet) computeTile(img image.Image, index int) tileResult {
+  square := img.Bounds().Dx() == img.Bounds().Dy()
+
+  orientations := []int{0, 2, 4, 6}
+  if square {
+    orientations = []int{0, 1, 2, 3, 4, 5, 6, 7}
+  }
+
+  variants := [8]image.Image{}
+  variants[0] = img
+  variants[2] = imaging.Rotate180(img)
+  variants[4] = imaging.FlipH(img)
+  variants[6] = imaging.Rotate180(variants[4])
+  if square {
+    variants[1] = imaging.Rotate90(img)
+    variants[3] = imaging.Rotate270(img)
+    variants[5] = imaging.Rotate90(variants[4])
+    variants[7] = imaging.Rotate270(variants[4])
+  }
+
+  result := tileResult{index: index, img: img, orientations: orientations}
+
+  for _, i := range orientations {
+    result.hashes[i] = Hash(variants[i])
+
+    if p.HashMode == HashModeExact || p.Threshold <= 0 {
+      continue
+    }
+
+    switch p.HashMode {
+    case HashModePHash:
+      result.fingerprints[i] = PHash(variants[i])
+    case HashModeRadon:
+      if result.signatures == nil {
+        result.signatures = make([][]float64, 8)
+      }
+      result.signatures[i] = RadonSignature(variants[i])
     }
+  }
 
+  return result
+}
+
+// commitTile looks up r's hashes against the prototype images gathered so
+// far, falling back to perceptual matching per p.HashMode, and either
+// records a match or adds r as a new prototype.  It mutates protoImages,
+// protoKeys, protoFingerprints, and protoSignatures, so the caller must not
+// run it concurrently for different tiles.
+func (p *ImageSet) commitTile(r tileResult) {
+  for _, i := range r.orientations {
+    hash := r.hashes[i]
     if _, ok := p.protoImages[hash]; ok {
-      found = true
-      p.orientations[index] = byte(i)
-      p.images[index] = hash
-      break
+      p.orientations[r.index] = byte(i)
+      p.images[r.index] = hash
+      return
     }
   }
 
-  if !found {
-    p.protoImages[firstHash] = img
-    p.images[index] = firstHash
-    p.orientations[index] = byte(0)
+  if p.HashMode != HashModeExact && p.Threshold > 0 {
+    if key, orientation, ok := p.findPerceptualMatch(r); ok {
+      p.orientations[r.index] = orientation
+      p.images[r.index] = key
+      return
+    }
+  }
+
+  firstHash := r.hashes[0]
+  p.protoImages[firstHash] = r.img
+  p.images[r.index] = firstHash
+  p.orientations[r.index] = byte(0)
+
+  if hash, e := blurhash.Encode(blurhashXComponents, blurhashYComponents, r.img); e == nil {
+    p.protoBlurhashes[firstHash] = hash
+  }
+
+  p.protoKeys = append(p.protoKeys, firstHash)
+  if p.HashMode != HashModeExact && p.Threshold > 0 {
+    switch p.HashMode {
+    case HashModePHash:
+      p.protoFingerprints = append(p.protoFingerprints, r.fingerprints[0])
+    case HashModeRadon:
+      p.protoSignatures = append(p.protoSignatures, r.signatures[0])
+    }
   }
 }
 
-// WriteImageFiles creates an images directory and creates files in it for each
-// prototype image.
+// findPerceptualMatch compares each of r's precomputed orientation
+// fingerprints/signatures against every prototype using p.HashMode's
+// distance function, and returns the prototype key and orientation index of
+// the closest match across all of them, if any falls within p.Threshold.
+func (p *ImageSet) findPerceptualMatch(r tileResult) (key uint64, orientation byte, ok bool) {
+  best := math.MaxFloat64
+
+  for _, i := range r.orientations {
+    var distanceTo func(j int) float64
+
+    switch p.HashMode {
+    case HashModePHash:
+      fingerprint := r.fingerprints[i]
+      distanceTo = func(j int) float64 { return float64(HammingDistance(fingerprint, p.protoFingerprints[j])) }
+    case HashModeRadon:
+      signature := r.signatures[i]
+      distanceTo = func(j int) float64 { return L2Distance(signature, p.protoSignatures[j]) }
+    default:
+      continue
+    }
+
+    for j, candidateKey := range p.protoKeys {
+      if d := distanceTo(j); d < best {
+        best = d
+        key = candidateKey
+        orientation = byte(i)
+        ok = true
+      }
+    }
+  }
+
+  if !ok || best > p.Threshold {
+    return 0, 0, false
+  }
+
+  return key, orientation, true
+}
+
+// WriteImageFiles serializes the prototype images according to p.Format: a
+// PNG per prototype in an images directory (the default), or a single FSH
+// bitmap container plus instance-ID manifest named "textures.fsh"/".dat".
 func (p *ImageSet) WriteImageFiles() {
+  switch p.Format {
+  case "fsh":
+    p.writeFshFile("textures.fsh")
+    return
+  case "dat":
+    p.writeFshFile("textures.dat")
+    return
+  }
+
   e := os.Mkdir("images", 0755)
   if e != nil {
     fmt.Fprintf(os.Stderr, "An error occurred while creating the images directory. Error: %s\n", e)
   }
 
+  sem := make(chan struct{}, p.numWorkers())
+  var wg sync.WaitGroup
+
   for k, v := range p.protoImages {
-    if file, e := os.Create(fmt.Sprintf("images/%x.png", k)); e == nil {
-      png.Encode(file, v)
-      file.Close()
-    }
+    wg.Add(1)
+    sem <- struct{}{}
+
+    go func(k uint64, v image.Image) {
+      defer wg.Done()
+      defer func() { <-sem }()
+
+      if file, e := os.Create(fmt.Sprintf("images/%x.png", k)); e == nil {
+        png.Encode(file, v)
+        file.Close()
+      }
+    }(k, v)
+  }
+
+  wg.Wait()
+}
+
+// writeFshFile serializes p.protoImages as a single FSH file at path using
+// p.FshEncoding.
+func (p *ImageSet) writeFshFile(path string) {
+  writer := NewFshWriter(p.FshEncoding)
+  if e := writer.Write(path, p.protoImages); e != nil {
+    fmt.Fprintf(os.Stderr, "An error occurred while writing the FSH file %s. Error: %s\n", path, e)
   }
 }
 
-// GetX determines which grid column the provided index references.
+// GetX determines which grid column the provided index references.  For a
+// non-uniform grid (GridPath set) this is the tile's pixel X origin rather
+// than a column number.
 func (p *ImageSet) GetX(index int) int {
+  if p.cellX != nil {
+    return p.cellX[index]
+  }
   return index % p.stride
 }
 
-//GetY determines which grid row the provided index references.
+//GetY determines which grid row the provided index references.  For a
+// non-uniform grid (GridPath set) this is the tile's pixel Y origin rather
+// than a row number, since there's no consistent stride to derive one from.
 func (p *ImageSet) GetY(index int) int {
+  if p.cellY != nil {
+    return p.cellY[index]
+  }
   return int(index / p.stride)
 }
 
@@ -205,6 +676,10 @@ func (p *ImageSet) WriteReport(bounds image.Rectangle) {
     file.WriteString("\t\t\t\t<th>Unique Textures</th>\n")
     file.WriteString(fmt.Sprintf("\t\t\t\t<td>%d</td>\n", len(p.protoImages)))
     file.WriteString("\t\t\t</tr>\n")
+    file.WriteString("\t\t\t<tr>\n")
+    file.WriteString("\t\t\t\t<th>EXIF Orientation</th>\n")
+    file.WriteString(fmt.Sprintf("\t\t\t\t<td>%d</td>\n", p.SourceOrientation))
+    file.WriteString("\t\t\t</tr>\n")
     file.WriteString("\t\t</table>\n")
 
     file.WriteString("\t\t<h1>Output Images</h1>\n")
@@ -221,7 +696,7 @@ func (p *ImageSet) WriteReport(bounds image.Rectangle) {
       file.WriteString("\t\t\t<tr>\n")
       file.WriteString(fmt.Sprintf("\t\t\t\t<td>%d</td>\n", p.GetX(i)))
       file.WriteString(fmt.Sprintf("\t\t\t\t<td>%d</td>\n", p.GetY(i)))
-      file.WriteString(fmt.Sprintf("\t\t\t\t<td><a href='images/%x.png'><img src='images/%x.png' height='32' width='32'/></a>\n",v , v))
+      file.WriteString(fmt.Sprintf("\t\t\t\t<td><a href='images/%x.png'><div style='%s'><img src='images/%x.png' height='32' width='32'/></div></a>\n", v, blurhashBackgroundStyle(p.protoBlurhashes[v]), v))
       file.WriteString(fmt.Sprintf("\t\t\t\t<td>%x</td>\n", v))
       file.WriteString(fmt.Sprintf("\t\t\t\t<td>%s</td>\n", GetOrientationLabel(p.orientations[i])))
       file.WriteString("\t\t\t</tr>\n")
@@ -237,6 +712,32 @@ func (p *ImageSet) WriteReport(bounds image.Rectangle) {
   }
 }
 
+// blurhashBlurSize is the edge length, in pixels, of the placeholder image
+// decoded from a blurhash string for the HTML report's inline background.
+const blurhashBlurSize = 32
+
+// blurhashBackgroundStyle decodes hash into a small placeholder image and
+// returns a CSS "background" declaration embedding it as a data URI, so the
+// report shows a color-accurate placeholder while the real thumbnail loads.
+// It returns the empty string if hash is empty or fails to decode.
+func blurhashBackgroundStyle(hash string) string {
+  if hash == "" {
+    return ""
+  }
+
+  placeholder, e := blurhash.Decode(hash, blurhashBlurSize, blurhashBlurSize, 1)
+  if e != nil {
+    return ""
+  }
+
+  var buf bytes.Buffer
+  if e := png.Encode(&buf, placeholder); e != nil {
+    return ""
+  }
+
+  return fmt.Sprintf("background: url(data:image/png;base64,%s) center/cover no-repeat;", base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
 func GetOrientationLabel(orientation byte) string {
   switch orientation & 0x7 {
   case 0:
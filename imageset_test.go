@@ -0,0 +1,100 @@
+package main
+
+import (
+  "image"
+  "image/color"
+  "testing"
+)
+
+// solidTile returns an n x n tile filled with a single color, distinct per
+// seed so tiles with different seeds never hash equal.
+func solidTile(n int, seed uint8) image.Image {
+  img := image.NewRGBA(image.Rect(0, 0, n, n))
+  c := color.RGBA{R: seed, G: seed, B: seed, A: 255}
+  for y := 0; y < n; y++ {
+    for x := 0; x < n; x++ {
+      img.Set(x, y, c)
+    }
+  }
+  return img
+}
+
+func newTestImageSet(mode HashMode, threshold float64) *ImageSet {
+  return NewImageSet("", mode, threshold, "png", FshEncodingDXT1, 1, "", 16, 16, "")
+}
+
+func TestCommitTileExactHashMode(t *testing.T) {
+  p := newTestImageSet(HashModeExact, 0)
+  p.images = make([]uint64, 2)
+  p.orientations = make([]byte, 2)
+  p.protoImages = make(map[uint64]image.Image)
+  p.protoBlurhashes = make(map[uint64]string)
+
+  tile := solidTile(16, 7)
+  p.commitTile(p.computeTile(tile, 0))
+  p.commitTile(p.computeTile(tile, 1))
+
+  if len(p.protoImages) != 1 {
+    t.Errorf("got %d prototypes for two identical tiles, want 1", len(p.protoImages))
+  }
+  if p.images[0] != p.images[1] {
+    t.Errorf("identical tiles committed to different prototypes")
+  }
+}
+
+func TestCommitTilePHashMode(t *testing.T) {
+  p := newTestImageSet(HashModePHash, 5)
+  p.images = make([]uint64, 1)
+  p.orientations = make([]byte, 1)
+  p.protoImages = make(map[uint64]image.Image)
+  p.protoBlurhashes = make(map[uint64]string)
+
+  p.commitTile(p.computeTile(solidTile(16, 42), 0))
+
+  if len(p.protoImages) != 1 {
+    t.Errorf("got %d prototypes, want 1", len(p.protoImages))
+  }
+  if len(p.protoFingerprints) != 1 {
+    t.Errorf("got %d protoFingerprints, want 1", len(p.protoFingerprints))
+  }
+}
+
+// TestCommitTileRadonModeDoesNotPanic reproduces the reported crash: with
+// HashModeRadon and a threshold <= 0, computeTile never allocates
+// result.signatures, so commitTile must not unconditionally index into it
+// when recording a new prototype.
+func TestCommitTileRadonModeDoesNotPanic(t *testing.T) {
+  p := newTestImageSet(HashModeRadon, 0)
+  p.images = make([]uint64, 1)
+  p.orientations = make([]byte, 1)
+  p.protoImages = make(map[uint64]image.Image)
+  p.protoBlurhashes = make(map[uint64]string)
+
+  p.commitTile(p.computeTile(solidTile(16, 99), 0))
+
+  if len(p.protoImages) != 1 {
+    t.Errorf("got %d prototypes, want 1", len(p.protoImages))
+  }
+  if len(p.protoSignatures) != 0 {
+    t.Errorf("got %d protoSignatures with threshold <= 0, want 0", len(p.protoSignatures))
+  }
+}
+
+func TestCommitTileRadonModeWithThreshold(t *testing.T) {
+  p := newTestImageSet(HashModeRadon, 50)
+  p.images = make([]uint64, 2)
+  p.orientations = make([]byte, 2)
+  p.protoImages = make(map[uint64]image.Image)
+  p.protoBlurhashes = make(map[uint64]string)
+
+  tile := solidTile(16, 200)
+  p.commitTile(p.computeTile(tile, 0))
+  p.commitTile(p.computeTile(tile, 1))
+
+  if len(p.protoImages) != 1 {
+    t.Errorf("got %d prototypes for two identical tiles, want 1", len(p.protoImages))
+  }
+  if len(p.protoSignatures) != 1 {
+    t.Errorf("got %d protoSignatures, want 1", len(p.protoSignatures))
+  }
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+  "image"
+  "image/color"
+  "math"
+  "math/bits"
+  "sort"
+
+  "github.com/disintegration/imaging"
+)
+
+// HashMode selects the algorithm used to detect duplicate tiles when an
+// ImageSet processes a source image.
+type HashMode string
+
+const (
+  // HashModeExact compares tiles using Hash's exact FNV-1a digest.  Tiles
+  // must match byte-for-byte to be considered duplicates.
+  HashModeExact HashMode = "exact"
+
+  // HashModePHash compares tiles using a 64-bit DCT perceptual hash and a
+  // Hamming-distance threshold, so near-identical tiles (compression
+  // artifacts, subpixel resampling, palette rounding) are still matched.
+  HashModePHash HashMode = "phash"
+
+  // HashModeRadon compares tiles using a rotation-robust Radon projection
+  // signature and an L2-distance threshold.
+  HashModeRadon HashMode = "radon"
+)
+
+// pHashSize is the edge length, in pixels, that a tile is downscaled to
+// before the DCT is computed.
+const pHashSize = 32
+
+// pHashBlock is the edge length of the low-frequency DCT coefficient block
+// kept to build the fingerprint, including the discarded DC term at (0,0).
+const pHashBlock = 8
+
+// radonAngles is the number of evenly spaced angles in [0,180) sampled to
+// build a RadonSignature.
+const radonAngles = 18
+
+// PHash computes a 64-bit perceptual hash of img: it is downscaled to a
+// pHashSize x pHashSize greyscale image, a 2-D DCT is taken, and the
+// pHashBlock x pHashBlock low-frequency coefficients (excluding the DC term)
+// are compared against their median to produce one bit each.
+func PHash(img image.Image) uint64 {
+  grey := toGreyscale(imaging.Resize(img, pHashSize, pHashSize, imaging.Lanczos))
+  coeffs := dct2D(grey)
+
+  values := make([]float64, 0, pHashBlock*pHashBlock-1)
+  for y := 0; y < pHashBlock; y++ {
+    for x := 0; x < pHashBlock; x++ {
+      if x == 0 && y == 0 {
+        continue
+      }
+      values = append(values, coeffs[y][x])
+    }
+  }
+  median := medianOf(values)
+
+  var hash uint64
+  bit := uint(0)
+  for y := 0; y < pHashBlock; y++ {
+    for x := 0; x < pHashBlock; x++ {
+      if x == 0 && y == 0 {
+        continue
+      }
+      if coeffs[y][x] > median {
+        hash |= 1 << bit
+      }
+      bit++
+    }
+  }
+
+  return hash
+}
+
+// HammingDistance returns the number of bits that differ between a and b.
+func HammingDistance(a, b uint64) int {
+  return bits.OnesCount64(a ^ b)
+}
+
+// RadonSignature computes a rotation-robust feature vector for img.  The
+// tile is downscaled to a square greyscale image, then for each of
+// radonAngles evenly spaced angles in [0,180) it is rotated, its columns are
+// summed into a 1-D projection, and the standard deviation of that
+// projection becomes one element of the signature.  Signatures are compared
+// with L2Distance.
+func RadonSignature(img image.Image) []float64 {
+  grey := imaging.Resize(img, pHashSize, pHashSize, imaging.Lanczos)
+  signature := make([]float64, radonAngles)
+
+  for i := 0; i < radonAngles; i++ {
+    angle := float64(i) * (180.0 / float64(radonAngles))
+    rotated := imaging.Rotate(grey, angle, color.Black)
+    signature[i] = stdDev(columnSums(toGreyscale(rotated)))
+  }
+
+  return signature
+}
+
+// L2Distance returns the Euclidean distance between two equal-length
+// feature vectors, as produced by RadonSignature.
+func L2Distance(a, b []float64) float64 {
+  sum := 0.0
+  for i := range a {
+    d := a[i] - b[i]
+    sum += d * d
+  }
+  return math.Sqrt(sum)
+}
+
+// toGreyscale converts img to a luma-weighted greyscale matrix indexed
+// [y][x].
+func toGreyscale(img image.Image) [][]float64 {
+  bounds := img.Bounds()
+  w, h := bounds.Dx(), bounds.Dy()
+
+  grey := make([][]float64, h)
+  for y := 0; y < h; y++ {
+    grey[y] = make([]float64, w)
+    for x := 0; x < w; x++ {
+      r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+      grey[y][x] = 0.299*float64(r>>8) + 0.587*float64(g>>8) + 0.114*float64(b>>8)
+    }
+  }
+
+  return grey
+}
+
+// dct2D computes the 2-D type-II discrete cosine transform of a square
+// greyscale matrix.
+func dct2D(grey [][]float64) [][]float64 {
+  n := len(grey)
+  out := make([][]float64, n)
+  for u := range out {
+    out[u] = make([]float64, n)
+  }
+
+  for u := 0; u < n; u++ {
+    for v := 0; v < n; v++ {
+      sum := 0.0
+      for y := 0; y < n; y++ {
+        for x := 0; x < n; x++ {
+          sum += grey[y][x] *
+            math.Cos((math.Pi/float64(n))*(float64(x)+0.5)*float64(u)) *
+            math.Cos((math.Pi/float64(n))*(float64(y)+0.5)*float64(v))
+        }
+      }
+
+      cu, cv := 1.0, 1.0
+      if u == 0 {
+        cu = 1.0 / math.Sqrt2
+      }
+      if v == 0 {
+        cv = 1.0 / math.Sqrt2
+      }
+
+      out[v][u] = 0.25 * cu * cv * sum
+    }
+  }
+
+  return out
+}
+
+// columnSums sums each column of a greyscale matrix into a 1-D projection.
+func columnSums(grey [][]float64) []float64 {
+  if len(grey) == 0 {
+    return nil
+  }
+
+  sums := make([]float64, len(grey[0]))
+  for _, row := range grey {
+    for x, v := range row {
+      sums[x] += v
+    }
+  }
+
+  return sums
+}
+
+// stdDev returns the population standard deviation of values.
+func stdDev(values []float64) float64 {
+  if len(values) == 0 {
+    return 0
+  }
+
+  mean := 0.0
+  for _, v := range values {
+    mean += v
+  }
+  mean /= float64(len(values))
+
+  variance := 0.0
+  for _, v := range values {
+    d := v - mean
+    variance += d * d
+  }
+  variance /= float64(len(values))
+
+  return math.Sqrt(variance)
+}
+
+// medianOf returns the median of values.  The input slice is copied before
+// sorting so the caller's slice order is left untouched.
+func medianOf(values []float64) float64 {
+  sorted := make([]float64, len(values))
+  copy(sorted, values)
+  sort.Float64s(sorted)
+
+  mid := len(sorted) / 2
+  if len(sorted)%2 == 0 {
+    return (sorted[mid-1] + sorted[mid]) / 2
+  }
+  return sorted[mid]
+}
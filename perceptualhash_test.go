@@ -0,0 +1,91 @@
+package main
+
+import (
+  "image"
+  "image/color"
+  "testing"
+)
+
+// solidImage returns a uniformly colored w x h image.
+func solidImage(w, h int, c color.Color) image.Image {
+  img := image.NewRGBA(image.Rect(0, 0, w, h))
+  for y := 0; y < h; y++ {
+    for x := 0; x < w; x++ {
+      img.Set(x, y, c)
+    }
+  }
+  return img
+}
+
+// checkerImage returns a w x h image of n x n alternating black/white cells.
+func checkerImage(w, h, cell int) image.Image {
+  img := image.NewRGBA(image.Rect(0, 0, w, h))
+  for y := 0; y < h; y++ {
+    for x := 0; x < w; x++ {
+      if (x/cell+y/cell)%2 == 0 {
+        img.Set(x, y, color.White)
+      } else {
+        img.Set(x, y, color.Black)
+      }
+    }
+  }
+  return img
+}
+
+func TestHammingDistance(t *testing.T) {
+  cases := []struct {
+    a, b uint64
+    want int
+  }{
+    {0, 0, 0},
+    {0xFF, 0x00, 8},
+    {0x1, 0x3, 1},
+    {^uint64(0), 0, 64},
+  }
+
+  for _, c := range cases {
+    if got := HammingDistance(c.a, c.b); got != c.want {
+      t.Errorf("HammingDistance(%x, %x) = %d, want %d", c.a, c.b, got, c.want)
+    }
+  }
+}
+
+func TestPHashIdenticalImagesMatch(t *testing.T) {
+  img := checkerImage(64, 64, 8)
+
+  a := PHash(img)
+  b := PHash(img)
+
+  if d := HammingDistance(a, b); d != 0 {
+    t.Errorf("PHash of identical images differ by %d bits, want 0", d)
+  }
+}
+
+func TestPHashDistinguishesDissimilarImages(t *testing.T) {
+  solid := PHash(solidImage(64, 64, color.White))
+  checker := PHash(checkerImage(64, 64, 8))
+
+  if d := HammingDistance(solid, checker); d == 0 {
+    t.Errorf("PHash did not distinguish a solid image from a checkerboard")
+  }
+}
+
+func TestRadonSignatureIdenticalImagesMatch(t *testing.T) {
+  img := checkerImage(64, 64, 8)
+
+  a := RadonSignature(img)
+  b := RadonSignature(img)
+
+  if d := L2Distance(a, b); d != 0 {
+    t.Errorf("RadonSignature of identical images differ by %f, want 0", d)
+  }
+}
+
+func TestL2Distance(t *testing.T) {
+  a := []float64{0, 0, 0}
+  b := []float64{3, 4, 0}
+
+  if got := L2Distance(a, b); got != 5 {
+    t.Errorf("L2Distance(%v, %v) = %f, want 5", a, b, got)
+  }
+}
@@ -6,9 +6,18 @@ import (
 
 func main() {
   filename := flag.String("in", "", "The name of the file to load.")
+  hashMode := flag.String("hash", "exact", "The algorithm used to detect duplicate tiles: exact, phash, or radon.")
+  threshold := flag.Float64("threshold", 0, "The maximum distance under which two tiles are considered duplicates when -hash is phash or radon.")
+  format := flag.String("format", "png", "The format to write the unique tiles as: png, fsh, or dat.")
+  fshEncoding := flag.String("fsh-encoding", "dxt1", "The bitmap subformat used when -format is fsh or dat: dxt1, dxt3, or rgba32.")
+  workers := flag.Int("workers", 0, "The number of tiles to crop, hash, and encode concurrently. Defaults to runtime.NumCPU().")
+  serve := flag.String("serve", "", "If set, serve an interactive HTTP tile browser on this address (e.g. :8080) instead of writing report.html.")
+  tileWidth := flag.Int("tile-w", 128, "The width, in pixels, of each tile extracted from the source image.")
+  tileHeight := flag.Int("tile-h", 128, "The height, in pixels, of each tile extracted from the source image.")
+  grid := flag.String("grid", "", "Path to a sidecar JSON file listing pixel rectangles ({\"x\":,\"y\":,\"w\":,\"h\":}) to extract as tiles, for atlases whose tiles aren't all the same size. Overrides -tile-w/-tile-h.")
   flag.Parse()
 
-  imageSet := NewImageSet(*filename)
+  imageSet := NewImageSet(*filename, HashMode(*hashMode), *threshold, *format, FshEncoding(*fshEncoding), *workers, *serve, *tileWidth, *tileHeight, *grid)
   imageSet.Process()
 
 }
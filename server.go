@@ -0,0 +1,173 @@
+package main
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "image"
+  "image/png"
+  "net/http"
+  "strconv"
+  "strings"
+
+  "github.com/disintegration/imaging"
+)
+
+// Serve starts an HTTP server on addr that exposes the parsed tile grid
+// interactively, as an alternative to the static report.html + images/
+// output produced by WriteImageFiles and WriteReport.
+func (p *ImageSet) Serve(addr string) error {
+  mux := http.NewServeMux()
+  mux.HandleFunc("/tiles/", p.handleTile)
+  mux.HandleFunc("/proto/", p.handleProto)
+  mux.HandleFunc("/api/grid", p.handleGrid)
+
+  return http.ListenAndServe(addr, mux)
+}
+
+// handleTile serves /tiles/{z}/{x}/{y}.png: the prototype PNG at grid
+// position (x,y), with the tile's stored orientation applied on the fly.
+// The zoom level z is accepted for URL-scheme compatibility with
+// conventional tile servers but is otherwise unused, since the mosaic has a
+// single resolution.  Only available for uniform grids: a GridPath set of
+// tiles has no consistent row/column stride to address by (x,y), so those
+// should be browsed via /api/grid instead.
+func (p *ImageSet) handleTile(w http.ResponseWriter, r *http.Request) {
+  if p.cellX != nil {
+    http.NotFound(w, r)
+    return
+  }
+
+  parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/tiles/"), "/")
+  if len(parts) != 3 {
+    http.NotFound(w, r)
+    return
+  }
+
+  x, errX := strconv.Atoi(parts[1])
+  y, errY := strconv.Atoi(strings.TrimSuffix(parts[2], ".png"))
+  if errX != nil || errY != nil || x < 0 || y < 0 || x >= p.stride {
+    http.NotFound(w, r)
+    return
+  }
+
+  index := y*p.stride + x
+  if index < 0 || index >= len(p.images) {
+    http.NotFound(w, r)
+    return
+  }
+
+  hash := p.images[index]
+  proto, ok := p.protoImages[hash]
+  if !ok {
+    http.NotFound(w, r)
+    return
+  }
+
+  writePNGWithETag(w, r, hash, applyOrientationByte(proto, p.orientations[index]))
+}
+
+// handleProto serves /proto/{hash}.png: the raw prototype bytes for the
+// given hex hash, with no orientation applied.
+func (p *ImageSet) handleProto(w http.ResponseWriter, r *http.Request) {
+  hex := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/proto/"), ".png")
+
+  hash, e := strconv.ParseUint(hex, 16, 64)
+  if e != nil {
+    http.NotFound(w, r)
+    return
+  }
+
+  img, ok := p.protoImages[hash]
+  if !ok {
+    http.NotFound(w, r)
+    return
+  }
+
+  writePNGWithETag(w, r, hash, img)
+}
+
+// gridCell is one entry in the JSON payload served by handleGrid.
+type gridCell struct {
+  X           int    `json:"x"`
+  Y           int    `json:"y"`
+  Hash        string `json:"hash"`
+  Orientation byte   `json:"orientation"`
+  Blurhash    string `json:"blurhash,omitempty"`
+}
+
+// gridResponse is the JSON payload served by handleGrid.
+type gridResponse struct {
+  Stride int        `json:"stride"`
+  Rows   int        `json:"rows"`
+  Cells  []gridCell `json:"cells"`
+}
+
+// handleGrid serves /api/grid: the full tile grid as JSON, so a web UI can
+// render the mosaic without regenerating files.
+func (p *ImageSet) handleGrid(w http.ResponseWriter, r *http.Request) {
+  rows := 0
+  if p.stride > 0 {
+    rows = len(p.images) / p.stride
+  }
+
+  cells := make([]gridCell, len(p.images))
+  for i, hash := range p.images {
+    cells[i] = gridCell{
+      X:           p.GetX(i),
+      Y:           p.GetY(i),
+      Hash:        fmt.Sprintf("%x", hash),
+      Orientation: p.orientations[i],
+      Blurhash:    p.protoBlurhashes[hash],
+    }
+  }
+
+  w.Header().Set("Content-Type", "application/json")
+  json.NewEncoder(w).Encode(gridResponse{Stride: p.stride, Rows: rows, Cells: cells})
+}
+
+// writePNGWithETag serves img as a PNG with an ETag equal to hash, honoring
+// If-None-Match so browsers can cache prototype tiles aggressively.
+func writePNGWithETag(w http.ResponseWriter, r *http.Request, hash uint64, img image.Image) {
+  etag := fmt.Sprintf("%q", fmt.Sprintf("%x", hash))
+
+  w.Header().Set("ETag", etag)
+  if r.Header.Get("If-None-Match") == etag {
+    w.WriteHeader(http.StatusNotModified)
+    return
+  }
+
+  var buf bytes.Buffer
+  if e := png.Encode(&buf, img); e != nil {
+    http.Error(w, e.Error(), http.StatusInternalServerError)
+    return
+  }
+
+  w.Header().Set("Content-Type", "image/png")
+  w.Write(buf.Bytes())
+}
+
+// applyOrientationByte reverses computeTile's 8-orientation encoding, producing
+// the variant of proto that was originally stored at a tile with the given
+// orientation byte.
+func applyOrientationByte(proto image.Image, orientation byte) image.Image {
+  switch orientation & 0x7 {
+  case 0:
+    return proto
+  case 1:
+    return imaging.Rotate270(proto)
+  case 2:
+    return imaging.Rotate180(proto)
+  case 3:
+    return imaging.Rotate90(proto)
+  case 4:
+    return imaging.FlipH(proto)
+  case 5:
+    return imaging.Rotate90(imaging.FlipH(proto))
+  case 6:
+    return imaging.Rotate180(imaging.FlipH(proto))
+  case 7:
+    return imaging.Rotate270(imaging.FlipH(proto))
+  }
+  return proto
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+  "encoding/json"
+  "fmt"
+  "image"
+  "net/http"
+  "net/http/httptest"
+  "testing"
+)
+
+// newServedImageSet builds a four-tile ImageSet laid out as a 2x2 uniform
+// grid, ready to exercise the HTTP handlers without going through Process.
+func newServedImageSet() *ImageSet {
+  p := newTestImageSet(HashModeExact, 0)
+  p.protoImages = make(map[uint64]image.Image)
+  p.protoBlurhashes = make(map[uint64]string)
+  p.stride = 2
+
+  tiles := []image.Image{solidTile(16, 1), solidTile(16, 2), solidTile(16, 3), solidTile(16, 4)}
+  p.images = make([]uint64, len(tiles))
+  p.orientations = make([]byte, len(tiles))
+  for i, tile := range tiles {
+    p.commitTile(p.computeTile(tile, i))
+  }
+
+  return p
+}
+
+func TestHandleTileServesPNG(t *testing.T) {
+  p := newServedImageSet()
+
+  req := httptest.NewRequest(http.MethodGet, "/tiles/0/1/0.png", nil)
+  rec := httptest.NewRecorder()
+  p.handleTile(rec, req)
+
+  if rec.Code != http.StatusOK {
+    t.Fatalf("handleTile status = %d, want %d", rec.Code, http.StatusOK)
+  }
+  if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+    t.Errorf("Content-Type = %q, want image/png", ct)
+  }
+}
+
+func TestHandleTileRejectsNonUniformGrid(t *testing.T) {
+  p := newServedImageSet()
+  p.cellX = []int{0, 16, 0, 16}
+  p.cellY = []int{0, 0, 16, 16}
+
+  req := httptest.NewRequest(http.MethodGet, "/tiles/0/1/0.png", nil)
+  rec := httptest.NewRecorder()
+  p.handleTile(rec, req)
+
+  if rec.Code != http.StatusNotFound {
+    t.Errorf("handleTile status = %d, want %d for a non-uniform grid", rec.Code, http.StatusNotFound)
+  }
+}
+
+func TestHandleProtoServesPNG(t *testing.T) {
+  p := newServedImageSet()
+  hash := p.images[0]
+
+  req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/proto/%x.png", hash), nil)
+  rec := httptest.NewRecorder()
+  p.handleProto(rec, req)
+
+  if rec.Code != http.StatusOK {
+    t.Fatalf("handleProto status = %d, want %d", rec.Code, http.StatusOK)
+  }
+}
+
+func TestHandleGridReturnsAllCells(t *testing.T) {
+  p := newServedImageSet()
+
+  req := httptest.NewRequest(http.MethodGet, "/api/grid", nil)
+  rec := httptest.NewRecorder()
+  p.handleGrid(rec, req)
+
+  if rec.Code != http.StatusOK {
+    t.Fatalf("handleGrid status = %d, want %d", rec.Code, http.StatusOK)
+  }
+
+  var resp gridResponse
+  if e := json.Unmarshal(rec.Body.Bytes(), &resp); e != nil {
+    t.Fatalf("decoding /api/grid response: %v", e)
+  }
+
+  if len(resp.Cells) != len(p.images) {
+    t.Errorf("got %d cells, want %d", len(resp.Cells), len(p.images))
+  }
+  if resp.Stride != 2 {
+    t.Errorf("got stride %d, want 2", resp.Stride)
+  }
+}